@@ -0,0 +1,67 @@
+package recommendation
+
+import (
+	"time"
+
+	"github.com/spf13/pflag"
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// RateLimiterOptions holds the tunables for the Recommendation controller's
+// per-item and overall rate limiters. They are wired into SetupWithManager
+// via controller.Options.RateLimiter.
+type RateLimiterOptions struct {
+	// BaseDelay is the requeue delay applied to a key's first MaxFastAttempts
+	// retries.
+	BaseDelay time.Duration
+	// MaxDelay is the requeue delay applied once a key has been retried more
+	// than MaxFastAttempts times.
+	MaxDelay time.Duration
+	// MaxFastAttempts is the number of retries that use BaseDelay before a
+	// key falls back to MaxDelay.
+	MaxFastAttempts int
+	// BucketQPS and BucketBurst bound the overall reconcile rate across all
+	// Recommendations, so a storm of broken objects cannot starve healthy
+	// ones.
+	BucketQPS   float64
+	BucketBurst int
+}
+
+// NewRateLimiterOptions returns the default Recommendation controller rate
+// limiter options: up to 200 near-immediate retries at 50ms, falling back to
+// a 1000s ceiling, globally capped at 5 reconciles/s with a burst of 20.
+func NewRateLimiterOptions() *RateLimiterOptions {
+	return &RateLimiterOptions{
+		BaseDelay:       50 * time.Millisecond,
+		MaxDelay:        1000 * time.Second,
+		MaxFastAttempts: 200,
+		BucketQPS:       5,
+		BucketBurst:     20,
+	}
+}
+
+// AddFlags registers the rate limiter flags on the controller-manager's flag
+// set.
+func (o *RateLimiterOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.DurationVar(&o.BaseDelay, "recommendation-rate-limiter-base-delay", o.BaseDelay,
+		"Per-item requeue delay for the Recommendation controller before a key exhausts its fast-retry budget.")
+	fs.DurationVar(&o.MaxDelay, "recommendation-rate-limiter-max-delay", o.MaxDelay,
+		"Per-item requeue delay for the Recommendation controller once a key has exhausted its fast-retry budget.")
+	fs.IntVar(&o.MaxFastAttempts, "recommendation-rate-limiter-max-fast-attempts", o.MaxFastAttempts,
+		"Number of retries that use the base delay before a Recommendation key falls back to the max delay.")
+	fs.Float64Var(&o.BucketQPS, "recommendation-rate-limiter-qps", o.BucketQPS,
+		"Overall reconciles-per-second budget shared by all Recommendations.")
+	fs.IntVar(&o.BucketBurst, "recommendation-rate-limiter-burst", o.BucketBurst,
+		"Burst size for the overall Recommendation reconcile rate limiter.")
+}
+
+// RateLimiter builds the workqueue.RateLimiter used by SetupWithManager: a
+// per-item fast/slow limiter composed with an overall token bucket limiter,
+// via workqueue.MaxOfRateLimiter so the stricter of the two always wins.
+func (o *RateLimiterOptions) RateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemFastSlowRateLimiter(o.BaseDelay, o.MaxDelay, o.MaxFastAttempts),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(o.BucketQPS), o.BucketBurst)},
+	)
+}
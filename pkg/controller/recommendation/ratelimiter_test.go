@@ -0,0 +1,68 @@
+package recommendation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiterOptionsDefaults(t *testing.T) {
+	o := NewRateLimiterOptions()
+
+	if o.BaseDelay != 50*time.Millisecond {
+		t.Errorf("BaseDelay = %v, want %v", o.BaseDelay, 50*time.Millisecond)
+	}
+	if o.MaxDelay != 1000*time.Second {
+		t.Errorf("MaxDelay = %v, want %v", o.MaxDelay, 1000*time.Second)
+	}
+	if o.MaxFastAttempts != 200 {
+		t.Errorf("MaxFastAttempts = %d, want 200", o.MaxFastAttempts)
+	}
+	if o.BucketQPS != 5 {
+		t.Errorf("BucketQPS = %v, want 5", o.BucketQPS)
+	}
+	if o.BucketBurst != 20 {
+		t.Errorf("BucketBurst = %d, want 20", o.BucketBurst)
+	}
+}
+
+func TestRateLimiterFallsBackToMaxDelayAfterFastAttempts(t *testing.T) {
+	o := &RateLimiterOptions{
+		BaseDelay:       time.Millisecond,
+		MaxDelay:        time.Second,
+		MaxFastAttempts: 2,
+		BucketQPS:       1000,
+		BucketBurst:     1000,
+	}
+	limiter := o.RateLimiter()
+
+	item := "recommendation-key"
+	for i := 0; i < o.MaxFastAttempts; i++ {
+		if d := limiter.When(item); d > o.MaxDelay {
+			t.Fatalf("When() attempt %d = %v, want <= MaxDelay (%v)", i, d, o.MaxDelay)
+		}
+	}
+
+	if d := limiter.When(item); d < o.MaxDelay {
+		t.Errorf("When() after MaxFastAttempts = %v, want >= MaxDelay (%v)", d, o.MaxDelay)
+	}
+}
+
+func TestRateLimiterForgetResetsAttempts(t *testing.T) {
+	o := &RateLimiterOptions{
+		BaseDelay:       time.Millisecond,
+		MaxDelay:        time.Second,
+		MaxFastAttempts: 1,
+		BucketQPS:       1000,
+		BucketBurst:     1000,
+	}
+	limiter := o.RateLimiter()
+
+	item := "recommendation-key"
+	limiter.When(item)
+	limiter.When(item)
+	limiter.Forget(item)
+
+	if n := limiter.NumRequeues(item); n != 0 {
+		t.Errorf("NumRequeues() after Forget = %d, want 0", n)
+	}
+}
@@ -16,6 +16,8 @@ import (
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
 	predictionapi "github.com/gocrane/api/prediction/v1alpha1"
@@ -23,24 +25,37 @@ import (
 	"github.com/gocrane/crane/pkg/prediction"
 	"github.com/gocrane/crane/pkg/providers"
 	"github.com/gocrane/crane/pkg/recommend"
+	"github.com/gocrane/crane/pkg/recommendation/conditions"
+	"github.com/gocrane/crane/pkg/recommendation/history"
+	"github.com/gocrane/crane/pkg/recommendation/rollout"
 )
 
 const (
 	RsyncPeriod           = 60 * time.Second
-	ErrorFallbackPeriod   = 5 * time.Second
 	DefaultTimeoutSeconds = int32(600)
+
+	// DefaultRolloutStepDuration is used between Progressive rollout steps
+	// when Spec.RolloutStrategy.StepDuration is unset.
+	DefaultRolloutStepDuration = 5 * time.Minute
+
+	// RecommendationCleanupFinalizer is added to a Recommendation on its
+	// first reconcile and only removed once the predictor and provider
+	// query handles it opened have been torn down, so deleting a
+	// Recommendation never leaks a running prediction.
+	RecommendationCleanupFinalizer = "analysis.crane.io/recommendation-cleanup"
 )
 
 // Controller is responsible for reconcile Recommendation
 type Controller struct {
 	client.Client
-	ConfigSet   *analysisv1alph1.ConfigSet
-	Scheme      *runtime.Scheme
-	Recorder    record.EventRecorder
-	RestMapper  meta.RESTMapper
-	ScaleClient scale.ScalesGetter
-	Predictors  map[predictionapi.AlgorithmType]prediction.Interface
-	Provider    providers.Interface
+	ConfigSet          *analysisv1alph1.ConfigSet
+	Scheme             *runtime.Scheme
+	Recorder           record.EventRecorder
+	RestMapper         meta.RESTMapper
+	ScaleClient        scale.ScalesGetter
+	Predictors         map[predictionapi.AlgorithmType]prediction.Interface
+	Provider           providers.Interface
+	RateLimiterOptions *RateLimiterOptions
 }
 
 func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
@@ -56,10 +71,34 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	}
 
 	if recommendation.DeletionTimestamp != nil {
-		// todo stop prediction
+		if !controllerutil.ContainsFinalizer(recommendation, RecommendationCleanupFinalizer) {
+			return ctrl.Result{}, nil
+		}
+
+		if err := c.stopPrediction(recommendation); err != nil {
+			msg := fmt.Sprintf("Failed to stop prediction, Recommendation %s error %v", klog.KObj(recommendation), err)
+			klog.Errorf(msg)
+			c.Recorder.Event(recommendation, v1.EventTypeWarning, "FailedStopPrediction", err.Error())
+			// Retry through the rate limiter until teardown succeeds; the
+			// finalizer stays in place so the object cannot be garbage
+			// collected with a dangling prediction.
+			return ctrl.Result{}, err
+		}
+
+		controllerutil.RemoveFinalizer(recommendation, RecommendationCleanupFinalizer)
+		if err := c.Update(ctx, recommendation); err != nil {
+			return ctrl.Result{}, err
+		}
 		return ctrl.Result{}, nil
 	}
 
+	if !controllerutil.ContainsFinalizer(recommendation, RecommendationCleanupFinalizer) {
+		controllerutil.AddFinalizer(recommendation, RecommendationCleanupFinalizer)
+		if err := c.Update(ctx, recommendation); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
 	needRecommend, needResync := c.NeedRecommend(recommendation)
 	if !needRecommend {
 		if needResync {
@@ -77,34 +116,98 @@ func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 
 	newStatus := recommendation.Status.DeepCopy()
 
+	if recommendation.Spec.RollbackTo != nil {
+		return c.rollback(ctx, recommendation, newStatus)
+	}
+
 	recommender, err := recommend.NewRecommender(c.Client, c.RestMapper, c.ScaleClient, recommendation, c.Predictors, c.Provider, c.ConfigSet)
 	if err != nil {
 		c.Recorder.Event(recommendation, v1.EventTypeNormal, "FailedCreateRecommender", err.Error())
 		msg := fmt.Sprintf("Failed to create recommender, Recommendation %s error %v", klog.KObj(recommendation), err)
 		klog.Errorf(msg)
-		setCondition(newStatus, "Ready", metav1.ConditionFalse, "FailedCreateRecommender", msg)
+		conditions.Set(newStatus, metav1.Condition{Type: conditions.RecommenderReady, Status: metav1.ConditionFalse, Reason: "FailedCreateRecommender", Message: msg})
+		conditions.Set(newStatus, metav1.Condition{Type: conditions.Ready, Status: metav1.ConditionFalse, Reason: "FailedCreateRecommender", Message: msg})
 		c.UpdateStatus(ctx, recommendation, newStatus)
+		// Let the workqueue rate limiter compute the next retry instead of a
+		// fixed RequeueAfter, so a flapping Recommendation backs off on its
+		// own cadence.
 		return ctrl.Result{}, err
 	}
+	conditions.Set(newStatus, metav1.Condition{Type: conditions.RecommenderReady, Status: metav1.ConditionTrue, Reason: "RecommenderReady", Message: "Recommender is ready"})
 
 	proposed, err := recommender.Offer()
 	if err != nil {
 		c.Recorder.Event(recommendation, v1.EventTypeNormal, "FailedOfferRecommendation", err.Error())
 		msg := fmt.Sprintf("Failed to offer recommend, Recommendation %s error %v", klog.KObj(recommendation), err)
 		klog.Errorf(msg)
-		setCondition(newStatus, "Ready", metav1.ConditionFalse, "FailedOfferRecommend", msg)
+		conditions.Set(newStatus, metav1.Condition{Type: conditions.PredictorReady, Status: metav1.ConditionFalse, Reason: "FailedOfferRecommend", Message: msg})
+		conditions.Set(newStatus, metav1.Condition{Type: conditions.ProviderReady, Status: metav1.ConditionFalse, Reason: "FailedOfferRecommend", Message: msg})
+		conditions.Set(newStatus, metav1.Condition{Type: conditions.Ready, Status: metav1.ConditionFalse, Reason: "FailedOfferRecommend", Message: msg})
 		c.UpdateStatus(ctx, recommendation, newStatus)
-		return ctrl.Result{
-			RequeueAfter: ErrorFallbackPeriod,
-		}, err
+		return ctrl.Result{}, err
 	}
+	conditions.Set(newStatus, metav1.Condition{Type: conditions.PredictorReady, Status: metav1.ConditionTrue, Reason: "PredictorReady", Message: "Predictor is ready"})
+	conditions.Set(newStatus, metav1.Condition{Type: conditions.ProviderReady, Status: metav1.ConditionTrue, Reason: "ProviderReady", Message: "Provider is ready"})
 
 	if proposed != nil {
 		newStatus.ResourceRequest = proposed.ResourceRequest
 		newStatus.EffectiveHPA = proposed.EffectiveHPA
+
+		threshold := history.DefaultThresholdPercent
+		if recommendation.Spec.HistoryThreshold != nil {
+			threshold = *recommendation.Spec.HistoryThreshold
+		}
+		limit := history.DefaultLimit
+		if recommendation.Spec.HistoryLimit != nil {
+			limit = *recommendation.Spec.HistoryLimit
+		}
+		history.Record(newStatus, analysisv1alph1.RecommendationSnapshot{
+			Timestamp:       metav1.Now(),
+			Algorithm:       proposed.Algorithm,
+			ResourceRequest: proposed.ResourceRequest,
+			EffectiveHPA:    proposed.EffectiveHPA,
+		}, threshold, limit)
+
+		if recommendation.Spec.RolloutStrategy != nil && recommendation.Spec.RolloutStrategy.Type != analysisv1alph1.RolloutStrategyManual {
+			stepDuration := DefaultRolloutStepDuration
+			if recommendation.Spec.RolloutStrategy.StepDuration != nil {
+				stepDuration = recommendation.Spec.RolloutStrategy.StepDuration.Duration
+			}
+
+			reconciler := rollout.NewReconciler(c.Client, c.RestMapper, c.ScaleClient, c.Recorder, stepDuration)
+			rolloutCond, requeue, rolloutErr := reconciler.Apply(ctx, recommendation, conditions.Get(newStatus, rollout.ConditionType), proposed)
+			if rolloutCond.Type != "" {
+				conditions.Set(newStatus, rolloutCond)
+			}
+			if rolloutErr != nil {
+				msg := fmt.Sprintf("Failed to roll out Recommendation %s: %v", klog.KObj(recommendation), rolloutErr)
+				klog.Errorf(msg)
+				// Do not overwrite Ready=True on top of a genuine rollout
+				// failure: that would report success while Rollout sits at
+				// False, and would stop the workqueue from retrying through
+				// the rate limiter.
+				conditions.Set(newStatus, metav1.Condition{Type: conditions.Ready, Status: metav1.ConditionFalse, Reason: "RolloutFailed", Message: msg})
+				c.UpdateStatus(ctx, recommendation, newStatus)
+				return ctrl.Result{}, rolloutErr
+			}
+			if requeue {
+				conditions.Set(newStatus, metav1.Condition{Type: conditions.Ready, Status: metav1.ConditionTrue, Reason: "RecommendationReady", Message: "Recommendation is ready"})
+				c.UpdateStatus(ctx, recommendation, newStatus)
+				return ctrl.Result{RequeueAfter: stepDuration}, nil
+			}
+			if rolloutCond.Reason == rollout.ReasonRolloutAborted {
+				// The rollout aborted without a Go error (a replica
+				// regression, reported only through rolloutCond), but it is
+				// still a failure: Ready must not say True while Rollout
+				// sits at False/RolloutAborted.
+				conditions.Set(newStatus, metav1.Condition{Type: conditions.Ready, Status: metav1.ConditionFalse, Reason: rollout.ReasonRolloutAborted, Message: rolloutCond.Message})
+				c.UpdateStatus(ctx, recommendation, newStatus)
+				return ctrl.Result{}, nil
+			}
+		}
 	}
 
-	setCondition(newStatus, "Ready", metav1.ConditionTrue, "RecommendationReady", "Recommendation is ready")
+	conditions.Set(newStatus, metav1.Condition{Type: conditions.Ready, Status: metav1.ConditionTrue, Reason: "RecommendationReady", Message: "Recommendation is ready"})
 	c.UpdateStatus(ctx, recommendation, newStatus)
 	return ctrl.Result{}, nil
 }
@@ -147,21 +250,25 @@ func (c *Controller) NeedRecommend(recommendation *analysisv1alph1.Recommendatio
 	return true, false
 }
 
+// UpdateStatus writes newStatus back to the Recommendation. It is a no-op
+// whenever newStatus is identical to the object's current status other than
+// LastUpdateTime: since conditions.Set only bumps a condition's
+// LastTransitionTime when its Status actually transitions, a periodic
+// reconcile that changes nothing no longer defeats this guard and forces a
+// spurious write.
 func (c *Controller) UpdateStatus(ctx context.Context, recommendation *analysisv1alph1.Recommendation, newStatus *analysisv1alph1.RecommendationStatus) {
-	if !equality.Semantic.DeepEqual(&recommendation.Status, newStatus) {
+	oldStatus := recommendation.Status.DeepCopy()
+	oldStatus.LastUpdateTime = metav1.Time{}
+	comparableNewStatus := newStatus.DeepCopy()
+	comparableNewStatus.LastUpdateTime = metav1.Time{}
+
+	if !equality.Semantic.DeepEqual(oldStatus, comparableNewStatus) {
 		klog.V(4).Infof("Recommendation status should be updated, currentStatus %v newStatus %v", &recommendation.Status, newStatus)
 
 		recommendation.Status = *newStatus
 		recommendation.Status.LastUpdateTime = metav1.Now()
 
-		var ready = false
-		for _, cond := range newStatus.Conditions {
-			if cond.Reason == "RecommendationReady" && cond.Status == metav1.ConditionTrue {
-				ready = true
-				break
-			}
-		}
-		if ready {
+		if conditions.IsTrue(newStatus, conditions.Ready) && conditions.GetReason(newStatus, conditions.Ready) == "RecommendationReady" {
 			recommendation.Status.LastSuccessfulTime = &recommendation.Status.LastUpdateTime
 		}
 
@@ -176,27 +283,61 @@ func (c *Controller) UpdateStatus(ctx context.Context, recommendation *analysisv
 	}
 }
 
+// rollback re-emits the Status.History snapshot named by
+// Spec.RollbackTo as the current recommendation and suppresses new offers
+// until RollbackTo is cleared.
+func (c *Controller) rollback(ctx context.Context, recommendation *analysisv1alph1.Recommendation, newStatus *analysisv1alph1.RecommendationStatus) (ctrl.Result, error) {
+	snapshot := history.FindSnapshot(newStatus, *recommendation.Spec.RollbackTo)
+	if snapshot == nil {
+		msg := fmt.Sprintf("No recommendation snapshot found at %s to roll back to", recommendation.Spec.RollbackTo)
+		klog.Errorf(msg)
+		conditions.Set(newStatus, metav1.Condition{Type: conditions.Ready, Status: metav1.ConditionFalse, Reason: "RollbackSnapshotNotFound", Message: msg})
+		c.UpdateStatus(ctx, recommendation, newStatus)
+		return ctrl.Result{}, nil
+	}
+
+	newStatus.ResourceRequest = snapshot.ResourceRequest
+	newStatus.EffectiveHPA = snapshot.EffectiveHPA
+	conditions.Set(newStatus, metav1.Condition{
+		Type:    conditions.Ready,
+		Status:  metav1.ConditionTrue,
+		Reason:  "RolledBack",
+		Message: fmt.Sprintf("Re-emitting the recommendation snapshot from %s", snapshot.Timestamp),
+	})
+	c.UpdateStatus(ctx, recommendation, newStatus)
+	return ctrl.Result{RequeueAfter: RsyncPeriod}, nil
+}
+
+// stopPrediction tears down the predictor subscriptions and provider query
+// handles that a running Recommendation may have opened, so that deleting
+// it does not leak them. It rebuilds the recommender for the object being
+// deleted and closes it symmetrically with how Reconcile creates it.
+func (c *Controller) stopPrediction(recommendation *analysisv1alph1.Recommendation) error {
+	recommender, err := recommend.NewRecommender(c.Client, c.RestMapper, c.ScaleClient, recommendation, c.Predictors, c.Provider, c.ConfigSet)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// The target workload is already gone, so there is nothing left
+			// to tear down.
+			klog.Warningf("Target of Recommendation %s is gone, nothing to tear down: %v", klog.KObj(recommendation), err)
+			return nil
+		}
+		// A transient failure rebuilding the recommender must not be treated
+		// as "already gone": that would strip the finalizer while leaking
+		// whatever predictor/provider state is actually still open. Return
+		// the error so the caller retries through the rate limiter instead.
+		return fmt.Errorf("rebuild recommender while finalizing Recommendation %s: %w", klog.KObj(recommendation), err)
+	}
+
+	return recommender.Close()
+}
+
 func (c *Controller) SetupWithManager(mgr ctrl.Manager) error {
+	if c.RateLimiterOptions == nil {
+		c.RateLimiterOptions = NewRateLimiterOptions()
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&analysisv1alph1.Recommendation{}).
+		WithOptions(controller.Options{RateLimiter: c.RateLimiterOptions.RateLimiter()}).
 		Complete(c)
 }
-
-func setCondition(status *analysisv1alph1.RecommendationStatus, conditionType string, conditionStatus metav1.ConditionStatus, reason string, message string) {
-	for i := range status.Conditions {
-		if status.Conditions[i].Type == conditionType {
-			status.Conditions[i].Status = conditionStatus
-			status.Conditions[i].Reason = reason
-			status.Conditions[i].Message = message
-			status.Conditions[i].LastTransitionTime = metav1.Now()
-			return
-		}
-	}
-	status.Conditions = append(status.Conditions, metav1.Condition{
-		Type:               conditionType,
-		Status:             conditionStatus,
-		Reason:             reason,
-		Message:            message,
-		LastTransitionTime: metav1.Now(),
-	})
-}
\ No newline at end of file
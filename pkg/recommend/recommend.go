@@ -0,0 +1,131 @@
+// Package recommend builds the Recommender for a single Recommendation
+// object and tears down the predictor subscriptions and provider query
+// handles it holds. Offer does not sample utilization or run the
+// predictors: the proposal it returns names an algorithm but carries no
+// ResourceRequest or EffectiveHPA.
+//
+// ResourceRequestProposed and ResourceRequestContainer are aliases of the
+// corresponding github.com/gocrane/api/analysis/v1alpha1 types, and
+// EffectiveHPA uses that package's type directly, so that
+// ProposedRecommendation's fields can be assigned directly onto a
+// RecommendationStatus. This package also assumes analysisv1alph1.Recommendation has a
+// Spec.Predictors []predictionapi.AlgorithmType field naming the algorithms
+// configured for that object; that field lives in github.com/gocrane/api
+// alongside RolloutStrategy (see the rollout package) and must land there in
+// a companion PR.
+package recommend
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/scale"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
+	predictionapi "github.com/gocrane/api/prediction/v1alpha1"
+
+	"github.com/gocrane/crane/pkg/prediction"
+	"github.com/gocrane/crane/pkg/providers"
+)
+
+// ResourceRequestContainer is a single container's proposed resource
+// requests.
+type ResourceRequestContainer = analysisv1alph1.ResourceRequestContainer
+
+// ResourceRequestProposed is the proposed resource request for every
+// container of a Recommendation's target.
+type ResourceRequestProposed = analysisv1alph1.ResourceRequestProposed
+
+// ProposedRecommendation is what Recommender.Offer returns: the proposal a
+// Controller copies onto RecommendationStatus.
+type ProposedRecommendation struct {
+	// Algorithm is the predictor that produced this proposal, recorded on
+	// the RecommendationSnapshot the controller appends to Status.History.
+	Algorithm       predictionapi.AlgorithmType
+	ResourceRequest *ResourceRequestProposed
+	EffectiveHPA    *analysisv1alph1.EffectiveHorizontalPodAutoscalerRecommendation
+}
+
+// Recommender computes and offers a recommendation for one Recommendation
+// object, and owns the predictor subscriptions and provider query handles
+// it opens to do so.
+type Recommender struct {
+	client         client.Client
+	restMapper     meta.RESTMapper
+	scaleClient    scale.ScalesGetter
+	recommendation *analysisv1alph1.Recommendation
+	predictors     map[predictionapi.AlgorithmType]prediction.Interface
+	provider       providers.Interface
+	configSet      *analysisv1alph1.ConfigSet
+}
+
+// NewRecommender returns a Recommender for recommendation that holds the
+// given client, predictors, provider and configSet. It does not validate
+// recommendation against configSet or the available predictors/provider,
+// and it never returns an error.
+func NewRecommender(
+	c client.Client,
+	restMapper meta.RESTMapper,
+	scaleClient scale.ScalesGetter,
+	recommendation *analysisv1alph1.Recommendation,
+	predictors map[predictionapi.AlgorithmType]prediction.Interface,
+	provider providers.Interface,
+	configSet *analysisv1alph1.ConfigSet,
+) (*Recommender, error) {
+	return &Recommender{
+		client:         c,
+		restMapper:     restMapper,
+		scaleClient:    scaleClient,
+		recommendation: recommendation,
+		predictors:     predictors,
+		provider:       provider,
+		configSet:      configSet,
+	}, nil
+}
+
+// Offer returns a proposal whose Algorithm is the first entry of
+// Spec.Predictors (empty when none is configured). It does not sample
+// utilization or run the predictors; the returned ResourceRequest and
+// EffectiveHPA are always nil.
+func (r *Recommender) Offer() (*ProposedRecommendation, error) {
+	var algorithm predictionapi.AlgorithmType
+	if len(r.recommendation.Spec.Predictors) > 0 {
+		algorithm = r.recommendation.Spec.Predictors[0]
+	}
+	return &ProposedRecommendation{Algorithm: algorithm}, nil
+}
+
+// Close tears down the predictor subscriptions and provider query handles
+// this Recommender opened while offering a recommendation, so deleting its
+// Recommendation does not leak them. It is the symmetric counterpart to
+// NewRecommender, invoked by the controller's finalizer teardown.
+//
+// It only tears down the algorithms recommendation.Spec.Predictors actually
+// configures for this object, not every algorithm the controller happens to
+// have registered: DeleteQuery on an algorithm this Recommendation never
+// used would needlessly fail teardown and block the finalizer forever.
+func (r *Recommender) Close() error {
+	var errs []error
+
+	for _, algorithm := range r.recommendation.Spec.Predictors {
+		predictor, ok := r.predictors[algorithm]
+		if !ok || predictor == nil {
+			continue
+		}
+		if err := predictor.DeleteQuery(r.recommendation.Namespace, r.recommendation.Name); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s predictor: %w", algorithm, err))
+		}
+	}
+
+	if r.provider != nil {
+		if err := r.provider.Close(r.recommendation.Namespace, r.recommendation.Name); err != nil {
+			errs = append(errs, fmt.Errorf("release provider query handles: %w", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("close recommender for %s/%s: %v", r.recommendation.Namespace, r.recommendation.Name, errs)
+	}
+	return nil
+}
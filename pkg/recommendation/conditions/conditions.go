@@ -0,0 +1,89 @@
+// Package conditions provides helpers for setting and querying the status
+// conditions carried on a Recommendation, modeled on the common Kubernetes
+// "conditions getter" pattern: Set only bumps LastTransitionTime when a
+// condition's Status actually transitions, so a no-op reconcile does not
+// defeat UpdateStatus's equality guard and produce a spurious write.
+package conditions
+
+import (
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
+)
+
+// Well-known Recommendation condition types. Ready summarizes the overall
+// reconcile outcome; the others let callers wait on, or alert on, the
+// specific stage that is failing instead of parsing the Ready message.
+const (
+	Ready            = "Ready"
+	PredictorReady   = "PredictorReady"
+	ProviderReady    = "ProviderReady"
+	RecommenderReady = "RecommenderReady"
+)
+
+// Set updates the condition of cond.Type on status in place: a new
+// condition is appended, an existing one is overwritten, and
+// LastTransitionTime is only bumped when Status actually changes. Resulting
+// conditions are kept sorted by Type for deterministic output.
+func Set(status *analysisv1alph1.RecommendationStatus, cond metav1.Condition) {
+	if existing := Get(status, cond.Type); existing != nil && existing.Status == cond.Status {
+		cond.LastTransitionTime = existing.LastTransitionTime
+	} else if cond.LastTransitionTime.IsZero() {
+		cond.LastTransitionTime = metav1.Now()
+	}
+
+	conditions := make([]metav1.Condition, 0, len(status.Conditions)+1)
+	found := false
+	for _, c := range status.Conditions {
+		if c.Type == cond.Type {
+			conditions = append(conditions, cond)
+			found = true
+			continue
+		}
+		conditions = append(conditions, c)
+	}
+	if !found {
+		conditions = append(conditions, cond)
+	}
+
+	sort.Slice(conditions, func(i, j int) bool {
+		return conditions[i].Type < conditions[j].Type
+	})
+	status.Conditions = conditions
+}
+
+// Get returns the condition of the given type, or nil if it is not present.
+func Get(status *analysisv1alph1.RecommendationStatus, conditionType string) *metav1.Condition {
+	for i := range status.Conditions {
+		if status.Conditions[i].Type == conditionType {
+			return &status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// IsTrue returns true if the condition of the given type is present and has
+// status True.
+func IsTrue(status *analysisv1alph1.RecommendationStatus, conditionType string) bool {
+	cond := Get(status, conditionType)
+	return cond != nil && cond.Status == metav1.ConditionTrue
+}
+
+// IsFalse returns true if the condition of the given type is present and has
+// status False.
+func IsFalse(status *analysisv1alph1.RecommendationStatus, conditionType string) bool {
+	cond := Get(status, conditionType)
+	return cond != nil && cond.Status == metav1.ConditionFalse
+}
+
+// GetReason returns the Reason of the condition of the given type, or "" if
+// the condition is not present.
+func GetReason(status *analysisv1alph1.RecommendationStatus, conditionType string) string {
+	cond := Get(status, conditionType)
+	if cond == nil {
+		return ""
+	}
+	return cond.Reason
+}
@@ -0,0 +1,103 @@
+package conditions
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
+)
+
+func TestSetAppendsNewCondition(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+
+	Set(status, metav1.Condition{Type: Ready, Status: metav1.ConditionTrue, Reason: "RecommendationReady"})
+
+	cond := Get(status, Ready)
+	if cond == nil {
+		t.Fatal("Get(Ready) = nil, want the condition just Set")
+	}
+	if cond.LastTransitionTime.IsZero() {
+		t.Error("LastTransitionTime is zero, want it stamped on first Set")
+	}
+}
+
+func TestSetPreservesLastTransitionTimeWhenStatusUnchanged(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+	Set(status, metav1.Condition{Type: Ready, Status: metav1.ConditionTrue, Reason: "RecommendationReady"})
+	first := Get(status, Ready).LastTransitionTime
+
+	Set(status, metav1.Condition{Type: Ready, Status: metav1.ConditionTrue, Reason: "StillReady"})
+	second := Get(status, Ready)
+
+	if second.LastTransitionTime != first {
+		t.Errorf("LastTransitionTime changed on a no-op Status transition: got %v, want %v", second.LastTransitionTime, first)
+	}
+	if second.Reason != "StillReady" {
+		t.Errorf("Reason = %q, want %q (non-transition fields should still update)", second.Reason, "StillReady")
+	}
+}
+
+func TestSetBumpsLastTransitionTimeOnStatusChange(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+	Set(status, metav1.Condition{Type: Ready, Status: metav1.ConditionTrue, Reason: "RecommendationReady"})
+	first := Get(status, Ready).LastTransitionTime
+
+	Set(status, metav1.Condition{Type: Ready, Status: metav1.ConditionFalse, Reason: "RolloutFailed"})
+	second := Get(status, Ready)
+
+	if second.LastTransitionTime == first {
+		t.Error("LastTransitionTime unchanged across a real Status transition, want it bumped")
+	}
+}
+
+func TestSetKeepsConditionsSortedByType(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+	Set(status, metav1.Condition{Type: Ready, Status: metav1.ConditionTrue})
+	Set(status, metav1.Condition{Type: PredictorReady, Status: metav1.ConditionTrue})
+	Set(status, metav1.Condition{Type: RecommenderReady, Status: metav1.ConditionTrue})
+
+	for i := 1; i < len(status.Conditions); i++ {
+		if status.Conditions[i-1].Type > status.Conditions[i].Type {
+			t.Errorf("Conditions not sorted: %q appears before %q", status.Conditions[i-1].Type, status.Conditions[i].Type)
+		}
+	}
+}
+
+func TestGetMissingConditionReturnsNil(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+	if got := Get(status, Ready); got != nil {
+		t.Errorf("Get() on empty status = %+v, want nil", got)
+	}
+}
+
+func TestIsTrueIsFalse(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+	Set(status, metav1.Condition{Type: Ready, Status: metav1.ConditionTrue})
+	Set(status, metav1.Condition{Type: ProviderReady, Status: metav1.ConditionFalse})
+
+	if !IsTrue(status, Ready) {
+		t.Error("IsTrue(Ready) = false, want true")
+	}
+	if IsFalse(status, Ready) {
+		t.Error("IsFalse(Ready) = true, want false")
+	}
+	if !IsFalse(status, ProviderReady) {
+		t.Error("IsFalse(ProviderReady) = false, want true")
+	}
+	if IsTrue(status, RecommenderReady) {
+		t.Error("IsTrue on a missing condition = true, want false")
+	}
+}
+
+func TestGetReason(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+	Set(status, metav1.Condition{Type: Ready, Status: metav1.ConditionFalse, Reason: "RolloutFailed"})
+
+	if got := GetReason(status, Ready); got != "RolloutFailed" {
+		t.Errorf("GetReason(Ready) = %q, want %q", got, "RolloutFailed")
+	}
+	if got := GetReason(status, PredictorReady); got != "" {
+		t.Errorf("GetReason on a missing condition = %q, want \"\"", got)
+	}
+}
@@ -0,0 +1,162 @@
+// Package history maintains the bounded, FIFO time series of past
+// recommendation proposals kept in Status.History, so operators can diff
+// what Crane recommended in the past against today's proposal, and so a
+// Recommendation can be rolled back to a previous proposal via
+// Spec.RollbackTo.
+//
+// PrintColumns renders that history as additionalPrinterColumns-style rows
+// so a `kubectl crane recommendation history <name>` subcommand (or
+// `kubectl get recommendation <name> -o custom-columns=...`) has something
+// ready-made to print; this package does not itself register a kubectl
+// plugin subcommand.
+//
+// This package assumes analysisv1alph1.RecommendationStatus already carries
+// a History []RecommendationSnapshot field and that Recommendation.Spec has
+// HistoryThreshold/HistoryLimit/RollbackTo fields; those live in
+// github.com/gocrane/api and must land there in a companion PR before this
+// package builds against a real checkout of the API module.
+package history
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
+)
+
+const (
+	// DefaultThresholdPercent is used when Spec.HistoryThreshold is unset:
+	// a per-container request must move by at least this many percent for a
+	// new snapshot to be recorded.
+	DefaultThresholdPercent = int32(5)
+
+	// DefaultLimit is used when Spec.HistoryLimit is unset.
+	DefaultLimit = int32(20)
+)
+
+// Record appends snapshot to status.History when it drifts materially from
+// the most recent snapshot, and evicts the oldest snapshots beyond limit.
+// It returns the snapshot actually recorded, or nil if snapshot was
+// discarded as indistinguishable from the previous one.
+func Record(status *analysisv1alph1.RecommendationStatus, snapshot analysisv1alph1.RecommendationSnapshot, threshold, limit int32) *analysisv1alph1.RecommendationSnapshot {
+	if threshold <= 0 {
+		threshold = DefaultThresholdPercent
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	reason := driftReason(latest(status), snapshot, threshold)
+	if reason == "" {
+		return nil
+	}
+	snapshot.DriftReason = reason
+
+	status.History = append(status.History, snapshot)
+	if overflow := len(status.History) - int(limit); overflow > 0 {
+		status.History = status.History[overflow:]
+	}
+	return &status.History[len(status.History)-1]
+}
+
+// FindSnapshot returns the snapshot in status.History recorded at
+// timestamp, or nil if none matches.
+func FindSnapshot(status *analysisv1alph1.RecommendationStatus, timestamp metav1.Time) *analysisv1alph1.RecommendationSnapshot {
+	for i := range status.History {
+		if status.History[i].Timestamp.Equal(&timestamp) {
+			return &status.History[i]
+		}
+	}
+	return nil
+}
+
+func latest(status *analysisv1alph1.RecommendationStatus) *analysisv1alph1.RecommendationSnapshot {
+	if len(status.History) == 0 {
+		return nil
+	}
+	return &status.History[len(status.History)-1]
+}
+
+// driftReason explains why snapshot should be recorded as a new entry, or
+// returns "" when it is within threshold percent of last for every
+// container resource and should be discarded.
+func driftReason(last *analysisv1alph1.RecommendationSnapshot, snapshot analysisv1alph1.RecommendationSnapshot, threshold int32) string {
+	if snapshot.ResourceRequest == nil {
+		// Nothing but a hollow entry would be recorded for an HPA-only
+		// recommendation; wait for a snapshot that actually carries data.
+		return ""
+	}
+	if last == nil {
+		return "initial recommendation"
+	}
+	if last.ResourceRequest == nil {
+		return ""
+	}
+
+	for _, container := range snapshot.ResourceRequest.Containers {
+		previous := findContainer(last.ResourceRequest.Containers, container.ContainerName)
+		if previous == nil {
+			return fmt.Sprintf("container %s added", container.ContainerName)
+		}
+		for resourceName, target := range container.Target {
+			previousTarget, ok := previous.Target[resourceName]
+			if !ok {
+				return fmt.Sprintf("container %s resource %s added", container.ContainerName, resourceName)
+			}
+			if percentChange(previousTarget, target) >= float64(threshold) {
+				return fmt.Sprintf("container %s %s changed by at least %d%%", container.ContainerName, resourceName, threshold)
+			}
+		}
+	}
+	return ""
+}
+
+func findContainer(containers []analysisv1alph1.ResourceRequestContainer, name string) *analysisv1alph1.ResourceRequestContainer {
+	for i := range containers {
+		if containers[i].ContainerName == name {
+			return &containers[i]
+		}
+	}
+	return nil
+}
+
+// HistoryColumn is one row of a printable summary of status.History, in
+// oldest-first order. It mirrors the columns a `kubectl crane recommendation
+// history <name>` subcommand, or a `kubectl get -o custom-columns=...`
+// invocation against additionalPrinterColumns, would want to show.
+type HistoryColumn struct {
+	Timestamp   metav1.Time
+	DriftReason string
+}
+
+// PrintColumns renders status.History as HistoryColumns for display.
+func PrintColumns(status *analysisv1alph1.RecommendationStatus) []HistoryColumn {
+	columns := make([]HistoryColumn, 0, len(status.History))
+	for _, snapshot := range status.History {
+		columns = append(columns, HistoryColumn{
+			Timestamp:   snapshot.Timestamp,
+			DriftReason: snapshot.DriftReason,
+		})
+	}
+	return columns
+}
+
+// percentChange returns the absolute percent difference of next relative to
+// previous. A previous value of zero is treated as a 100% change whenever
+// next is non-zero, since there is no finite percentage otherwise.
+func percentChange(previous, next resource.Quantity) float64 {
+	prevMilli := previous.MilliValue()
+	if prevMilli == 0 {
+		if next.MilliValue() == 0 {
+			return 0
+		}
+		return 100
+	}
+	delta := next.MilliValue() - prevMilli
+	if delta < 0 {
+		delta = -delta
+	}
+	return float64(delta) / float64(prevMilli) * 100
+}
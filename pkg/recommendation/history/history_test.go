@@ -0,0 +1,156 @@
+package history
+
+import (
+	"fmt"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
+)
+
+func containerSnapshot(cpu string) analysisv1alph1.RecommendationSnapshot {
+	return analysisv1alph1.RecommendationSnapshot{
+		ResourceRequest: &analysisv1alph1.ResourceRequestProposed{
+			Containers: []analysisv1alph1.ResourceRequestContainer{
+				{
+					ContainerName: "main",
+					Target: map[v1.ResourceName]resource.Quantity{
+						v1.ResourceCPU: resource.MustParse(cpu),
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestRecordInitialSnapshotAlwaysRecorded(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+
+	recorded := Record(status, containerSnapshot("100m"), 5, 10)
+	if recorded == nil {
+		t.Fatal("Record() = nil, want initial snapshot recorded")
+	}
+	if recorded.DriftReason != "initial recommendation" {
+		t.Errorf("DriftReason = %q, want %q", recorded.DriftReason, "initial recommendation")
+	}
+	if len(status.History) != 1 {
+		t.Fatalf("len(status.History) = %d, want 1", len(status.History))
+	}
+}
+
+func TestRecordDiscardsWithinThreshold(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+	Record(status, containerSnapshot("100m"), 5, 10)
+
+	recorded := Record(status, containerSnapshot("102m"), 5, 10)
+	if recorded != nil {
+		t.Errorf("Record() = %+v, want nil (2%% change is within 5%% threshold)", recorded)
+	}
+	if len(status.History) != 1 {
+		t.Fatalf("len(status.History) = %d, want 1 (no new snapshot appended)", len(status.History))
+	}
+}
+
+func TestRecordDriftBeyondThreshold(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+	Record(status, containerSnapshot("100m"), 5, 10)
+
+	recorded := Record(status, containerSnapshot("200m"), 5, 10)
+	if recorded == nil {
+		t.Fatal("Record() = nil, want a new snapshot recorded for a 100% change")
+	}
+	if len(status.History) != 2 {
+		t.Fatalf("len(status.History) = %d, want 2", len(status.History))
+	}
+}
+
+func TestRecordSkipsHollowInitialSnapshot(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+
+	recorded := Record(status, analysisv1alph1.RecommendationSnapshot{}, 5, 10)
+	if recorded != nil {
+		t.Errorf("Record() = %+v, want nil for a snapshot with no ResourceRequest", recorded)
+	}
+	if len(status.History) != 0 {
+		t.Fatalf("len(status.History) = %d, want 0", len(status.History))
+	}
+
+	recorded = Record(status, containerSnapshot("100m"), 5, 10)
+	if recorded == nil {
+		t.Fatal("Record() = nil, want the first snapshot carrying data to be recorded")
+	}
+	if recorded.DriftReason != "initial recommendation" {
+		t.Errorf("DriftReason = %q, want %q", recorded.DriftReason, "initial recommendation")
+	}
+}
+
+func TestRecordPopulatesAlgorithm(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+	snapshot := containerSnapshot("100m")
+	snapshot.Algorithm = "Percentile"
+
+	recorded := Record(status, snapshot, 5, 10)
+	if recorded == nil {
+		t.Fatal("Record() = nil, want initial snapshot recorded")
+	}
+	if recorded.Algorithm != "Percentile" {
+		t.Errorf("Algorithm = %q, want %q", recorded.Algorithm, "Percentile")
+	}
+}
+
+func TestRecordEvictsOldestBeyondLimit(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{}
+
+	for i := 0; i < 5; i++ {
+		Record(status, containerSnapshot(fmt.Sprintf("%dm", 100+i*100)), 5, 3)
+	}
+
+	if len(status.History) > 3 {
+		t.Fatalf("len(status.History) = %d, want at most 3", len(status.History))
+	}
+}
+
+func TestPercentChangeZeroBaseline(t *testing.T) {
+	cases := []struct {
+		name     string
+		previous string
+		next     string
+		want     float64
+	}{
+		{name: "zero to zero", previous: "0", next: "0", want: 0},
+		{name: "zero to nonzero", previous: "0", next: "100m", want: 100},
+		{name: "unchanged", previous: "100m", next: "100m", want: 0},
+		{name: "doubled", previous: "100m", next: "200m", want: 100},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := percentChange(resource.MustParse(c.previous), resource.MustParse(c.next))
+			if got != c.want {
+				t.Errorf("percentChange(%s, %s) = %v, want %v", c.previous, c.next, got, c.want)
+			}
+		})
+	}
+}
+
+func TestPrintColumns(t *testing.T) {
+	status := &analysisv1alph1.RecommendationStatus{
+		History: []analysisv1alph1.RecommendationSnapshot{
+			{Timestamp: metav1.Now(), DriftReason: "initial recommendation"},
+			{Timestamp: metav1.Now(), DriftReason: "container main cpu changed by at least 5%"},
+		},
+	}
+
+	columns := PrintColumns(status)
+	if len(columns) != len(status.History) {
+		t.Fatalf("len(PrintColumns(status)) = %d, want %d", len(columns), len(status.History))
+	}
+	for i, column := range columns {
+		if column.DriftReason != status.History[i].DriftReason {
+			t.Errorf("columns[%d].DriftReason = %q, want %q", i, column.DriftReason, status.History[i].DriftReason)
+		}
+	}
+}
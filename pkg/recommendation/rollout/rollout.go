@@ -0,0 +1,420 @@
+// Package rollout applies a Recommendation's proposed ResourceRequest to its
+// scale target, so a Recommendation configured with a Direct or Progressive
+// RolloutStrategy acts as a closed-loop controller instead of a purely
+// advisory one. A Progressive rollout is driven one step per Reconciler.Apply
+// call: the caller is expected to requeue after StepDuration until Apply
+// reports no further step is pending.
+//
+// This package assumes analysisv1alph1.Recommendation already carries a
+// Spec.RolloutStrategy field with RolloutStrategyManual/Direct/Progressive
+// values; that field lives in github.com/gocrane/api and must land there in
+// a companion PR before this package builds against a real checkout of the
+// API module.
+package rollout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/scale"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	analysisv1alph1 "github.com/gocrane/api/analysis/v1alpha1"
+
+	"github.com/gocrane/crane/pkg/recommend"
+)
+
+const (
+	// ConditionType is the Recommendation condition that reports rollout
+	// progress and outcome.
+	ConditionType = "Rollout"
+
+	ReasonRolloutSucceeded = "RolloutSucceeded"
+	ReasonRolloutAborted   = "RolloutAborted"
+
+	stepReasonPrefix = "RolloutStep:"
+
+	// baselineAnnotation stores the scale target's pre-rollout container
+	// resource requests, captured once when a Progressive rollout starts.
+	// Every step's fraction is computed against this fixed baseline instead
+	// of the live (already partially patched) object, so steps represent
+	// cumulative percentages of the original delta rather than compounding
+	// on top of each other.
+	baselineAnnotation = "rollout.crane.io/resource-request-baseline"
+)
+
+// baselineContainer is the pre-rollout resource requests of one container,
+// as persisted on baselineAnnotation.
+type baselineContainer struct {
+	ContainerName string            `json:"containerName"`
+	Requests      map[string]string `json:"requests"`
+}
+
+// DefaultSteps are the fractions of the proposed resource delta applied at
+// each step of a Progressive rollout, in order.
+var DefaultSteps = []float64{0.25, 0.5, 1.0}
+
+// Reconciler drives a single step of a Progressive or Direct rollout of a
+// Recommendation's proposed ResourceRequest onto its scale target.
+type Reconciler struct {
+	Client       client.Client
+	RestMapper   meta.RESTMapper
+	ScaleClient  scale.ScalesGetter
+	Recorder     record.EventRecorder
+	Steps        []float64
+	StepDuration time.Duration
+}
+
+// NewReconciler returns a Reconciler using the default step fractions.
+func NewReconciler(c client.Client, restMapper meta.RESTMapper, scaleClient scale.ScalesGetter, recorder record.EventRecorder, stepDuration time.Duration) *Reconciler {
+	return &Reconciler{
+		Client:       c,
+		RestMapper:   restMapper,
+		ScaleClient:  scaleClient,
+		Recorder:     recorder,
+		Steps:        DefaultSteps,
+		StepDuration: stepDuration,
+	}
+}
+
+// Apply advances the rollout of proposed onto recommendation's scale target
+// by one step, verifying against the previous step's Rollout condition
+// (currentCond, which may be nil). It returns the Rollout condition to
+// record and whether the caller should requeue after r.StepDuration to take
+// the next step.
+func (r *Reconciler) Apply(ctx context.Context, recommendation *analysisv1alph1.Recommendation, currentCond *metav1.Condition, proposed *recommend.ProposedRecommendation) (metav1.Condition, bool, error) {
+	if recommendation.Spec.RolloutStrategy == nil {
+		return metav1.Condition{}, false, nil
+	}
+
+	switch recommendation.Spec.RolloutStrategy.Type {
+	case analysisv1alph1.RolloutStrategyDirect:
+		// fraction 1.0 always resolves to the proposed target regardless of
+		// baseline, so Direct does not need one.
+		if err := r.patchResourceRequest(ctx, recommendation, nil, proposed, 1.0); err != nil {
+			return r.abortCondition(err), false, err
+		}
+		r.Recorder.Event(recommendation, v1.EventTypeNormal, "RolloutApplied", "Applied the recommended resource request directly")
+		return metav1.Condition{
+			Type:    ConditionType,
+			Status:  metav1.ConditionTrue,
+			Reason:  ReasonRolloutSucceeded,
+			Message: "Applied the recommended resource request directly",
+		}, false, nil
+
+	case analysisv1alph1.RolloutStrategyProgressive:
+		return r.step(ctx, recommendation, currentCond, proposed)
+
+	default:
+		return metav1.Condition{}, false, nil
+	}
+}
+
+func (r *Reconciler) step(ctx context.Context, recommendation *analysisv1alph1.Recommendation, currentCond *metav1.Condition, proposed *recommend.ProposedRecommendation) (metav1.Condition, bool, error) {
+	stepIndex := parseStepIndex(currentCond)
+
+	if stepIndex == 0 {
+		baseline, err := r.captureBaseline(ctx, recommendation)
+		if err != nil {
+			return r.abortCondition(err), false, err
+		}
+		if err := r.persistBaseline(ctx, recommendation, baseline); err != nil {
+			return r.abortCondition(err), false, err
+		}
+	}
+
+	baseline, err := r.loadBaseline(recommendation)
+	if err != nil {
+		return r.abortCondition(err), false, err
+	}
+
+	if stepIndex > 0 {
+		regressed, err := r.replicasRegressed(ctx, recommendation)
+		if err != nil {
+			return r.abortCondition(err), false, err
+		}
+		if regressed {
+			previousFraction := previousStepFraction(r.Steps, stepIndex)
+			if rerr := r.patchResourceRequest(ctx, recommendation, baseline, proposed, previousFraction); rerr != nil {
+				klog.Errorf("Failed to roll back Recommendation %s to step %d after a replica regression: %v", klog.KObj(recommendation), stepIndex-1, rerr)
+			}
+			r.Recorder.Eventf(recommendation, v1.EventTypeWarning, "RolloutAborted", "Replicas regressed after step %d/%d, rolled back to the previous step", stepIndex, len(r.Steps))
+			return metav1.Condition{
+				Type:    ConditionType,
+				Status:  metav1.ConditionFalse,
+				Reason:  ReasonRolloutAborted,
+				Message: fmt.Sprintf("Replicas regressed after step %d/%d, rolled back to the previous step", stepIndex, len(r.Steps)),
+			}, false, nil
+		}
+	}
+
+	if stepIndex >= len(r.Steps) {
+		r.clearBaseline(ctx, recommendation)
+		return metav1.Condition{
+			Type:    ConditionType,
+			Status:  metav1.ConditionTrue,
+			Reason:  ReasonRolloutSucceeded,
+			Message: fmt.Sprintf("Progressive rollout completed all %d steps", len(r.Steps)),
+		}, false, nil
+	}
+
+	fraction := r.Steps[stepIndex]
+	if err := r.patchResourceRequest(ctx, recommendation, baseline, proposed, fraction); err != nil {
+		return r.abortCondition(err), false, err
+	}
+
+	r.Recorder.Eventf(recommendation, v1.EventTypeNormal, "RolloutStep", "Applied step %d/%d (%.0f%% of the recommended delta)", stepIndex+1, len(r.Steps), fraction*100)
+
+	return metav1.Condition{
+		Type:    ConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  fmt.Sprintf("%s%d", stepReasonPrefix, stepIndex+1),
+		Message: fmt.Sprintf("Applied step %d/%d (%.0f%% of the recommended delta); verifying before the next step", stepIndex+1, len(r.Steps), fraction*100),
+	}, true, nil
+}
+
+// captureBaseline reads the scale target's current, pre-rollout container
+// resource requests, before the first Progressive step mutates them.
+func (r *Reconciler) captureBaseline(ctx context.Context, recommendation *analysisv1alph1.Recommendation) ([]baselineContainer, error) {
+	mapping, err := r.scaleTargetMapping(recommendation)
+	if err != nil {
+		return nil, err
+	}
+
+	target := &unstructured.Unstructured{}
+	target.SetGroupVersionKind(mapping.GroupVersionKind)
+	key := client.ObjectKey{Namespace: recommendation.Namespace, Name: recommendation.Spec.TargetRef.Name}
+	if err := r.Client.Get(ctx, key, target); err != nil {
+		return nil, fmt.Errorf("get scale target %s: %w", key, err)
+	}
+
+	containers, found, err := unstructured.NestedSlice(target.Object, "spec", "template", "spec", "containers")
+	if err != nil || !found {
+		return nil, fmt.Errorf("read containers of scale target %s: %w", key, err)
+	}
+
+	baseline := make([]baselineContainer, 0, len(containers))
+	for _, c := range containers {
+		container, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(container, "name")
+		requests, _, _ := unstructured.NestedStringMap(container, "resources", "requests")
+		baseline = append(baseline, baselineContainer{ContainerName: name, Requests: requests})
+	}
+	return baseline, nil
+}
+
+// persistBaseline records baseline on the Recommendation so it survives
+// across reconciles and is read back by every later step.
+func (r *Reconciler) persistBaseline(ctx context.Context, recommendation *analysisv1alph1.Recommendation, baseline []baselineContainer) error {
+	data, err := json.Marshal(baseline)
+	if err != nil {
+		return fmt.Errorf("marshal rollout baseline: %w", err)
+	}
+	if recommendation.Annotations == nil {
+		recommendation.Annotations = map[string]string{}
+	}
+	recommendation.Annotations[baselineAnnotation] = string(data)
+	return r.Client.Update(ctx, recommendation)
+}
+
+func (r *Reconciler) loadBaseline(recommendation *analysisv1alph1.Recommendation) ([]baselineContainer, error) {
+	data, ok := recommendation.Annotations[baselineAnnotation]
+	if !ok {
+		return nil, fmt.Errorf("missing rollout baseline annotation %s", baselineAnnotation)
+	}
+	var baseline []baselineContainer
+	if err := json.Unmarshal([]byte(data), &baseline); err != nil {
+		return nil, fmt.Errorf("unmarshal rollout baseline: %w", err)
+	}
+	return baseline, nil
+}
+
+// clearBaseline removes the baseline annotation once a rollout has
+// completed, so the next rollout captures a fresh one.
+func (r *Reconciler) clearBaseline(ctx context.Context, recommendation *analysisv1alph1.Recommendation) {
+	if _, ok := recommendation.Annotations[baselineAnnotation]; !ok {
+		return
+	}
+	delete(recommendation.Annotations, baselineAnnotation)
+	if err := r.Client.Update(ctx, recommendation); err != nil {
+		klog.Errorf("Failed to clear rollout baseline annotation on Recommendation %s: %v", klog.KObj(recommendation), err)
+	}
+}
+
+func findBaselineContainer(baseline []baselineContainer, name string) *baselineContainer {
+	for i := range baseline {
+		if baseline[i].ContainerName == name {
+			return &baseline[i]
+		}
+	}
+	return nil
+}
+
+func (r *Reconciler) abortCondition(err error) metav1.Condition {
+	return metav1.Condition{
+		Type:    ConditionType,
+		Status:  metav1.ConditionFalse,
+		Reason:  ReasonRolloutAborted,
+		Message: err.Error(),
+	}
+}
+
+// parseStepIndex recovers how many Progressive rollout steps have already
+// been applied from the previous Rollout condition's Reason.
+func parseStepIndex(cond *metav1.Condition) int {
+	if cond == nil || !strings.HasPrefix(cond.Reason, stepReasonPrefix) {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(cond.Reason, stepReasonPrefix))
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+// previousStepFraction returns the fraction that was in effect before the
+// step that just regressed. stepIndex is the count of steps already
+// applied, so the step that regressed is steps[stepIndex-1]; the one before
+// it is steps[stepIndex-2], or the untouched baseline (fraction 0) if the
+// very first step regressed.
+func previousStepFraction(steps []float64, stepIndex int) float64 {
+	if stepIndex < 2 {
+		return 0
+	}
+	return steps[stepIndex-2]
+}
+
+// replicasRegressed reports whether the scale target's observed replica
+// count (Status.Replicas of the scale subresource) has fallen below its
+// desired replica count (Spec.Replicas). The scale subresource carries no
+// Ready count, so replicas that exist but are not Ready are not reported as
+// a regression.
+func (r *Reconciler) replicasRegressed(ctx context.Context, recommendation *analysisv1alph1.Recommendation) (bool, error) {
+	mapping, err := r.scaleTargetMapping(recommendation)
+	if err != nil {
+		return false, err
+	}
+
+	scaleObj, err := r.ScaleClient.Scales(recommendation.Namespace).Get(ctx, mapping.Resource.GroupResource(), recommendation.Spec.TargetRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return false, fmt.Errorf("get scale subresource for %s: %w", recommendation.Spec.TargetRef.Name, err)
+	}
+
+	return scaleObj.Status.Replicas < scaleObj.Spec.Replicas, nil
+}
+
+// patchResourceRequest sets each proposed container's resource requests to
+// fraction of the way from baseline (the pre-rollout values captured once
+// per rollout) to the proposed target, and writes the result back to the
+// scale target's pod template. Computing every step against the fixed
+// baseline, rather than re-reading the live (already partially patched)
+// object, is what makes fraction a cumulative percentage of the original
+// delta instead of compounding step over step.
+func (r *Reconciler) patchResourceRequest(ctx context.Context, recommendation *analysisv1alph1.Recommendation, baseline []baselineContainer, proposed *recommend.ProposedRecommendation, fraction float64) error {
+	if proposed == nil || proposed.ResourceRequest == nil {
+		return nil
+	}
+
+	mapping, err := r.scaleTargetMapping(recommendation)
+	if err != nil {
+		return err
+	}
+
+	target := &unstructured.Unstructured{}
+	target.SetGroupVersionKind(mapping.GroupVersionKind)
+	key := client.ObjectKey{Namespace: recommendation.Namespace, Name: recommendation.Spec.TargetRef.Name}
+	if err := r.Client.Get(ctx, key, target); err != nil {
+		return fmt.Errorf("get scale target %s: %w", key, err)
+	}
+
+	containers, found, err := unstructured.NestedSlice(target.Object, "spec", "template", "spec", "containers")
+	if err != nil || !found {
+		return fmt.Errorf("read containers of scale target %s: %w", key, err)
+	}
+
+	for _, proposedContainer := range proposed.ResourceRequest.Containers {
+		baselineContainer := findBaselineContainer(baseline, proposedContainer.ContainerName)
+
+		for i := range containers {
+			container, ok := containers[i].(map[string]interface{})
+			if !ok || container["name"] != proposedContainer.ContainerName {
+				continue
+			}
+
+			requests, _, _ := unstructured.NestedStringMap(container, "resources", "requests")
+			if requests == nil {
+				requests = map[string]string{}
+			}
+			for resourceName, targetQuantity := range proposedContainer.Target {
+				var baselineStr string
+				if baselineContainer != nil {
+					baselineStr = baselineContainer.Requests[resourceName.String()]
+				}
+				requests[resourceName.String()] = blend(baselineStr, targetQuantity, fraction)
+			}
+			if err := unstructured.SetNestedStringMap(container, requests, "resources", "requests"); err != nil {
+				return fmt.Errorf("set resources of container %s: %w", proposedContainer.ContainerName, err)
+			}
+			containers[i] = container
+		}
+	}
+
+	if err := unstructured.SetNestedSlice(target.Object, containers, "spec", "template", "spec", "containers"); err != nil {
+		return fmt.Errorf("write containers of scale target %s: %w", key, err)
+	}
+
+	return r.Client.Update(ctx, target)
+}
+
+func (r *Reconciler) scaleTargetMapping(recommendation *analysisv1alph1.Recommendation) (*meta.RESTMapping, error) {
+	targetRef := recommendation.Spec.TargetRef
+	gv, err := schema.ParseGroupVersion(targetRef.APIVersion)
+	if err != nil {
+		return nil, fmt.Errorf("parse target apiVersion %q: %w", targetRef.APIVersion, err)
+	}
+
+	mapping, err := r.RestMapper.RESTMapping(gv.WithKind(targetRef.Kind).GroupKind(), gv.Version)
+	if err != nil {
+		return nil, fmt.Errorf("resolve REST mapping for scale target: %w", err)
+	}
+	return mapping, nil
+}
+
+// blend returns baselineStr moved fraction of the way toward target,
+// formatted as a resource.Quantity string. An empty or unparsable
+// baselineStr is treated as zero. Both baselineStr and fraction must refer
+// to the same fixed starting point across an entire rollout: calling this
+// with an already-blended value as baselineStr compounds fractions instead
+// of treating them as cumulative percentages of the original delta.
+func blend(baselineStr string, target resource.Quantity, fraction float64) string {
+	baseline := resource.MustParse("0")
+	if baselineStr != "" {
+		if q, err := resource.ParseQuantity(baselineStr); err == nil {
+			baseline = q
+		}
+	}
+
+	delta := target.DeepCopy()
+	delta.Sub(baseline)
+
+	scaled := *resource.NewMilliQuantity(int64(float64(delta.MilliValue())*fraction), target.Format)
+
+	result := baseline.DeepCopy()
+	result.Add(scaled)
+	return result.String()
+}
@@ -0,0 +1,101 @@
+package rollout
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBlendIsCumulativeNotCompounding(t *testing.T) {
+	target := resource.MustParse("400m")
+	baseline := "100m"
+
+	steps := []struct {
+		fraction float64
+		want     string
+	}{
+		{0.25, "175m"},
+		{0.5, "250m"},
+		{1.0, "400m"},
+	}
+
+	for _, s := range steps {
+		// Every step must be computed from the same baseline, not from the
+		// previous step's result, or fractions compound instead of
+		// representing cumulative percentages of the original delta.
+		got := blend(baseline, target, s.fraction)
+		gotQuantity := resource.MustParse(got)
+		wantQuantity := resource.MustParse(s.want)
+		if gotQuantity.MilliValue() != wantQuantity.MilliValue() {
+			t.Errorf("blend(%s, %s, %.2f) = %s, want %s", baseline, target.String(), s.fraction, got, s.want)
+		}
+	}
+}
+
+func TestBlendEmptyBaselineTreatedAsZero(t *testing.T) {
+	target := resource.MustParse("400m")
+
+	got := blend("", target, 0.5)
+	gotQuantity := resource.MustParse(got)
+	wantQuantity := resource.MustParse("200m")
+	if gotQuantity.MilliValue() != wantQuantity.MilliValue() {
+		t.Errorf("blend(\"\", %s, 0.5) = %s, want %s", target.String(), got, "200m")
+	}
+}
+
+func TestBlendFullFractionIgnoresBaseline(t *testing.T) {
+	target := resource.MustParse("400m")
+
+	for _, baseline := range []string{"", "1m", "999m"} {
+		got := blend(baseline, target, 1.0)
+		gotQuantity := resource.MustParse(got)
+		if gotQuantity.MilliValue() != target.MilliValue() {
+			t.Errorf("blend(%q, %s, 1.0) = %s, want %s", baseline, target.String(), got, target.String())
+		}
+	}
+}
+
+func TestPreviousStepFraction(t *testing.T) {
+	steps := []float64{0.25, 0.5, 1.0}
+
+	cases := []struct {
+		name      string
+		stepIndex int
+		want      float64
+	}{
+		{name: "first step regressed rolls back to baseline", stepIndex: 1, want: 0},
+		{name: "second step regressed rolls back to step 1's fraction", stepIndex: 2, want: 0.25},
+		{name: "third step regressed rolls back to step 2's fraction", stepIndex: 3, want: 0.5},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := previousStepFraction(steps, c.stepIndex); got != c.want {
+				t.Errorf("previousStepFraction(steps, %d) = %v, want %v", c.stepIndex, got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseStepIndex(t *testing.T) {
+	cases := []struct {
+		name string
+		cond *metav1.Condition
+		want int
+	}{
+		{name: "nil condition", cond: nil, want: 0},
+		{name: "no rollout condition yet", cond: &metav1.Condition{Reason: "RolloutSucceeded"}, want: 0},
+		{name: "step 1 applied", cond: &metav1.Condition{Reason: "RolloutStep:1"}, want: 1},
+		{name: "step 2 applied", cond: &metav1.Condition{Reason: "RolloutStep:2"}, want: 2},
+		{name: "malformed reason", cond: &metav1.Condition{Reason: "RolloutStep:not-a-number"}, want: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := parseStepIndex(c.cond); got != c.want {
+				t.Errorf("parseStepIndex(%+v) = %d, want %d", c.cond, got, c.want)
+			}
+		})
+	}
+}